@@ -0,0 +1,49 @@
+package nest_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/eyesight-tech/nest"
+	"github.com/eyesight-tech/nest/nesttest"
+)
+
+func TestDoRequestRetriesOnServerErrorsThenSucceeds(t *testing.T) {
+	server := nesttest.NewServer()
+	defer server.Close()
+	server.SetStructures(map[string]*nest.Structure{
+		"structure1": {StructureID: "structure1", Name: "Home"},
+	})
+
+	client := server.Client()
+	// Pre-populate RedirectURL so the redirect-discovery round trip doesn't
+	// consume one of the injected errors below.
+	client.RedirectURL = server.URL
+
+	server.InjectError("/structures.json", http.StatusInternalServerError)
+	server.InjectError("/structures.json", http.StatusTooManyRequests)
+
+	structures, apiErr := client.Structures()
+	if apiErr != nil {
+		t.Fatalf("Structures() after retryable 500/429 errors: %v", apiErr)
+	}
+	if got := structures["structure1"].Name; got != "Home" {
+		t.Errorf("Name = %q, want %q", got, "Home")
+	}
+}
+
+func TestDoRequestGivesUpOnNonRetryableError(t *testing.T) {
+	server := nesttest.NewServer()
+	defer server.Close()
+	server.SetStructures(map[string]*nest.Structure{
+		"structure1": {StructureID: "structure1", Name: "Home"},
+	})
+
+	client := server.Client()
+	client.RedirectURL = server.URL
+	server.InjectError("/structures.json", http.StatusBadRequest)
+
+	if _, apiErr := client.Structures(); apiErr == nil {
+		t.Fatal("Structures(): expected an error for a non-retryable 400 response, got nil")
+	}
+}
@@ -0,0 +1,197 @@
+package nest
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Away modes, used with Structure.SetAway
+const (
+	Home = iota
+	Away
+	AutoAway
+)
+
+// HvacMode modes, used with Thermostat.SetHvacMode
+const (
+	Cool = iota
+	Heat
+	HeatCool
+	Off
+	Eco
+)
+
+// Client is a Nest API client, holding the auth token and the API host to use.
+// RedirectURL is discovered on first use by following the 307 the Nest API
+// returns to point clients at their assigned regional host.
+type Client struct {
+	Token  string
+	APIURL string
+
+	// RedirectURL is discovered on first use; reads and writes go through
+	// redirectURL/setRedirectURLValue so concurrent streams and REST calls
+	// can share a Client safely. Pre-set it to skip discovery.
+	RedirectURL string
+
+	// ReadTimeout bounds how long a stream started by StructuresStream or
+	// DevicesStream will wait for a line of data (including SSE keep-alive
+	// comments) before treating the connection as stalled and reconnecting.
+	// Zero means defaultReadTimeout.
+	ReadTimeout time.Duration
+
+	// HTTPClient is the HTTPDoer used to make requests, defaulting to
+	// http.DefaultClient. Inject a fake or instrumented implementation to
+	// test code built on Client without hitting the network.
+	HTTPClient HTTPDoer
+
+	redirectMu sync.Mutex
+
+	eventIDs   map[string]string
+	eventIDsMu sync.Mutex
+}
+
+// readTimeout returns c.ReadTimeout, or defaultReadTimeout if unset.
+func (c *Client) readTimeout() time.Duration {
+	if c.ReadTimeout > 0 {
+		return c.ReadTimeout
+	}
+	return defaultReadTimeout
+}
+
+// redirectURL returns c.RedirectURL, synchronized so concurrent streams and
+// REST calls can share a Client.
+func (c *Client) redirectURL() string {
+	c.redirectMu.Lock()
+	defer c.redirectMu.Unlock()
+	return c.RedirectURL
+}
+
+// setRedirectURLValue records the Nest region-specific host discovered for
+// this client.
+func (c *Client) setRedirectURLValue(url string) {
+	c.redirectMu.Lock()
+	defer c.redirectMu.Unlock()
+	c.RedirectURL = url
+}
+
+// setRedirectURL discovers the Nest region-specific host this client's
+// token is assigned to, if it hasn't been already. It honors ctx for
+// cancellation and deadlines, applying defaultRequestTimeout to the
+// discovery request if ctx has none of its own, the same as any other
+// request Client makes.
+func (c *Client) setRedirectURL(ctx context.Context) {
+	if c.redirectURL() != "" {
+		return
+	}
+	attemptCtx := ctx
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(ctx, defaultRequestTimeout)
+		defer cancel()
+	}
+	resp, err := c.doHTTP(attemptCtx, "GET", c.APIURL+"/structures.json?auth="+c.Token, nil)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.Request.URL != nil {
+		c.setRedirectURLValue(resp.Request.URL.Scheme + "://" + resp.Request.URL.Host)
+	}
+}
+
+// lastEventID returns the most recently seen SSE event ID for url, used to
+// resume a stream via Last-Event-ID after a reconnect.
+func (c *Client) lastEventID(url string) string {
+	c.eventIDsMu.Lock()
+	defer c.eventIDsMu.Unlock()
+	return c.eventIDs[url]
+}
+
+// setLastEventID records the most recently seen SSE event ID for url.
+func (c *Client) setLastEventID(url, id string) {
+	c.eventIDsMu.Lock()
+	defer c.eventIDsMu.Unlock()
+	if c.eventIDs == nil {
+		c.eventIDs = make(map[string]string)
+	}
+	c.eventIDs[url] = id
+}
+
+// NewClient returns a new Nest API Client for the given OAuth token.
+//
+//	client := nest.NewClient(token)
+func NewClient(token string) *Client {
+	return &Client{
+		Token:  token,
+		APIURL: "https://developer-api.nest.com",
+	}
+}
+
+// Structure represents a Nest structure (home)
+// https://developer.nest.com/documentation/api#structures
+type Structure struct {
+	Client      *Client  `json:"-"`
+	StructureID string   `json:"structure_id"`
+	Name        string   `json:"name"`
+	Away        string   `json:"away"`
+	Thermostats []string `json:"thermostats"`
+}
+
+// Thermostat represents a Nest thermostat device
+// https://developer.nest.com/documentation/api#thermostats
+type Thermostat struct {
+	Client                    *Client `json:"-"`
+	DeviceID                  string  `json:"device_id"`
+	Name                      string  `json:"name"`
+	Label                     string  `json:"label"`
+	HvacMode                  string  `json:"hvac_mode"`
+	FanTimerActive            bool    `json:"fan_timer_active"`
+	AmbientTemperatureC       float32 `json:"ambient_temperature_c"`
+	AmbientTemperatureF       int     `json:"ambient_temperature_f"`
+	TargetTemperatureC        float32 `json:"target_temperature_c"`
+	TargetTemperatureF        int     `json:"target_temperature_f"`
+	TargetTemperatureHighC    float32 `json:"target_temperature_high_c"`
+	TargetTemperatureHighF    int     `json:"target_temperature_high_f"`
+	TargetTemperatureLowC     float32 `json:"target_temperature_low_c"`
+	TargetTemperatureLowF     int     `json:"target_temperature_low_f"`
+	Locked                    bool    `json:"is_locked"`
+	LockedTempMinC            float32 `json:"locked_temp_min_c"`
+	LockedTempMaxC            float32 `json:"locked_temp_max_c"`
+	LockedTempMinF            int     `json:"locked_temp_min_f"`
+	LockedTempMaxF            int     `json:"locked_temp_max_f"`
+	SunlightCorrectionEnabled bool    `json:"sunlight_correction_enabled"`
+	SunlightCorrectionActive  bool    `json:"sunlight_correction_active"`
+}
+
+// ETA represents an estimated time of arrival for a structure
+// https://developer.nest.com/documentation/eta-reference
+type ETA struct {
+	TripID                      string    `json:"trip_id"`
+	EstimatedArrivalWindowBegin time.Time `json:"estimated_arrival_window_begin"`
+	EstimatedArrivalWindowEnd   time.Time `json:"estimated_arrival_window_end"`
+}
+
+// APIError represents an error returned by the Nest API
+type APIError struct {
+	Error       string
+	Description string
+	Status      string
+	StatusCode  int
+
+	// RetryAfter is populated from the server's Retry-After header, if any,
+	// and used by Client's retrying request helper to pace retries.
+	RetryAfter time.Duration
+}
+
+// StructuresEvent is the envelope the Nest structures streaming API wraps
+// each structures payload in.
+type StructuresEvent struct {
+	Data map[string]*Structure `json:"data"`
+}
+
+// DevicesEvent is the envelope the Nest devices streaming API wraps each
+// thermostats payload in.
+type DevicesEvent struct {
+	Data map[string]*Thermostat `json:"data"`
+}
@@ -0,0 +1,117 @@
+package nesttest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"github.com/eyesight-tech/nest"
+)
+
+// fixture is one recorded request/response pair.
+type fixture struct {
+	Method     string `json:"method"`
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+	Body       string `json:"body"`
+}
+
+// Recorder is a nest.HTTPDoer that forwards every request to Doer and
+// records the request/response pair, rewriting the JSON fixture file at
+// Path with every pair this Recorder has recorded so far, so the
+// interactions can be served back later with a Replayer.
+//
+//	client := nest.NewClient(token)
+//	client.HTTPClient = nesttest.NewRecorder(http.DefaultClient, "testdata/structures.json")
+//	client.Structures()
+type Recorder struct {
+	Doer nest.HTTPDoer
+	Path string
+
+	mu       sync.Mutex
+	fixtures []fixture
+}
+
+// NewRecorder returns a Recorder that forwards requests to doer and
+// records them to path.
+func NewRecorder(doer nest.HTTPDoer, path string) *Recorder {
+	return &Recorder{Doer: doer, Path: path}
+}
+
+// Do implements nest.HTTPDoer.
+func (r *Recorder) Do(req *http.Request) (*http.Response, error) {
+	resp, err := r.Doer.Do(req)
+	if err != nil {
+		return resp, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	r.mu.Lock()
+	r.fixtures = append(r.fixtures, fixture{
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		StatusCode: resp.StatusCode,
+		Body:       string(body),
+	})
+	fixtures := append([]fixture(nil), r.fixtures...)
+	r.mu.Unlock()
+
+	data, err := json.MarshalIndent(fixtures, "", "  ")
+	if err != nil {
+		return resp, nil
+	}
+	ioutil.WriteFile(r.Path, data, 0644)
+	return resp, nil
+}
+
+// Replayer is a nest.HTTPDoer that serves back fixtures recorded by a
+// Recorder, matched by method and URL and consumed in the order they were
+// recorded.
+//
+//	replayer, _ := nesttest.NewReplayer("testdata/structures.json")
+//	client.HTTPClient = replayer
+type Replayer struct {
+	mu       sync.Mutex
+	fixtures []fixture
+}
+
+// NewReplayer loads the fixtures recorded at path.
+func NewReplayer(path string) (*Replayer, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fixtures []fixture
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		return nil, err
+	}
+	return &Replayer{fixtures: fixtures}, nil
+}
+
+// Do implements nest.HTTPDoer.
+func (r *Replayer) Do(req *http.Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, f := range r.fixtures {
+		if f.Method != req.Method || f.URL != req.URL.String() {
+			continue
+		}
+		r.fixtures = append(r.fixtures[:i:i], r.fixtures[i+1:]...)
+		return &http.Response{
+			StatusCode: f.StatusCode,
+			Status:     http.StatusText(f.StatusCode),
+			Header:     make(http.Header),
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(f.Body))),
+			Request:    req,
+		}, nil
+	}
+	return nil, fmt.Errorf("nesttest: no recorded fixture for %s %s", req.Method, req.URL.String())
+}
@@ -0,0 +1,47 @@
+package nesttest_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/eyesight-tech/nest"
+	"github.com/eyesight-tech/nest/nesttest"
+)
+
+func TestRecorderReplayerRoundTrip(t *testing.T) {
+	server := nesttest.NewServer()
+	defer server.Close()
+	server.SetStructures(map[string]*nest.Structure{
+		"structure1": {StructureID: "structure1", Name: "Home"},
+	})
+
+	fixturePath := filepath.Join(t.TempDir(), "structures.json")
+
+	recordingClient := server.Client()
+	recordingClient.HTTPClient = nesttest.NewRecorder(recordingClient.HTTPClient, fixturePath)
+
+	recorded, apiErr := recordingClient.Structures()
+	if apiErr != nil {
+		t.Fatalf("Structures() during recording: %v", apiErr)
+	}
+	if recorded["structure1"].Name != "Home" {
+		t.Fatalf("recorded Name = %q, want %q", recorded["structure1"].Name, "Home")
+	}
+
+	replayer, err := nesttest.NewReplayer(fixturePath)
+	if err != nil {
+		t.Fatalf("NewReplayer: %v", err)
+	}
+	replayingClient := nest.NewClient("test-token")
+	replayingClient.APIURL = server.URL
+	replayingClient.RedirectURL = server.URL
+	replayingClient.HTTPClient = replayer
+
+	replayed, apiErr := replayingClient.Structures()
+	if apiErr != nil {
+		t.Fatalf("Structures() during replay: %v", apiErr)
+	}
+	if replayed["structure1"].Name != "Home" {
+		t.Fatalf("replayed Name = %q, want %q", replayed["structure1"].Name, "Home")
+	}
+}
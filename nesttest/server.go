@@ -0,0 +1,308 @@
+/*
+Package nesttest provides a fake, in-process Nest REST + streaming API
+server for testing code built on nest.Client, plus a record/replay mode
+(see Recorder and Replayer) for capturing real API interactions into JSON
+fixtures and serving them back deterministically.
+
+	server := nesttest.NewServer()
+	defer server.Close()
+	server.SetThermostats(map[string]*nest.Thermostat{
+		"abc123": {DeviceID: "abc123", HvacMode: "heat"},
+	})
+	server.ScriptStream("/devices/thermostats.json", []nesttest.ScriptedEvent{
+		{ID: "1", Data: `{"data":{"abc123":{"device_id":"abc123","hvac_mode":"heat"}}}`},
+	})
+	client := server.Client()
+	handle := client.DevicesStream(ctx, func(thermostats map[string]*nest.Thermostat, err error) {
+		if err != nil {
+			return
+		}
+		thermostats["abc123"].SetHvacMode(nest.Cool)
+	})
+	defer handle.Stop()
+*/
+package nesttest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eyesight-tech/nest"
+)
+
+// ScriptedEvent is one SSE frame a streaming endpoint emits, in order, to
+// a connecting client.
+type ScriptedEvent struct {
+	ID    string
+	Event string
+	Data  string
+	Delay time.Duration
+
+	// Retry, if nonzero, is sent as the frame's "retry:" field, overriding
+	// the client's reconnect backoff from this point on.
+	Retry time.Duration
+
+	// Close, if true, ends the connection right after this event instead
+	// of falling through to keep-alive comments, so tests can exercise the
+	// client's reconnect-with-Last-Event-ID behavior.
+	Close bool
+}
+
+// Server is a fake Nest REST + streaming API server for tests. Construct
+// one with NewServer; the zero value is not usable.
+type Server struct {
+	*httptest.Server
+
+	mu             sync.Mutex
+	structures     map[string]*nest.Structure
+	thermostats    map[string]*nest.Thermostat
+	injectedErrors map[string][]int
+	redirectOnce   map[string]bool
+	scripts        map[string][]ScriptedEvent
+}
+
+// NewServer starts a fake Nest server. Call Close when done with it.
+func NewServer() *Server {
+	s := &Server{
+		structures:     make(map[string]*nest.Structure),
+		thermostats:    make(map[string]*nest.Thermostat),
+		injectedErrors: make(map[string][]int),
+		redirectOnce:   make(map[string]bool),
+		scripts:        make(map[string][]ScriptedEvent),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Client returns a nest.Client pointed at the fake server, with redirect
+// following disabled on its transport so the 307 region-handshake in
+// nest's request helper actually runs, the same as it would against the
+// real API.
+func (s *Server) Client() *nest.Client {
+	return &nest.Client{
+		Token:  "test-token",
+		APIURL: s.URL,
+		HTTPClient: &http.Client{
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// SetStructures replaces the structures the fake server serves over REST.
+// The streaming endpoint emits only the events set with ScriptStream.
+func (s *Server) SetStructures(structures map[string]*nest.Structure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.structures = structures
+}
+
+// SetThermostats replaces the thermostats the fake server serves over REST.
+// The streaming endpoint emits only the events set with ScriptStream.
+func (s *Server) SetThermostats(thermostats map[string]*nest.Thermostat) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.thermostats = thermostats
+}
+
+// InjectError makes the next request to path fail with status instead of
+// being handled normally. Injected errors for a path are consumed in the
+// order they were added.
+func (s *Server) InjectError(path string, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.injectedErrors[path] = append(s.injectedErrors[path], status)
+}
+
+// RedirectOncePath makes the next request to path receive a 307 back to
+// the fake server before being handled, exercising the client's
+// region-redirect handshake.
+func (s *Server) RedirectOncePath(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.redirectOnce[path] = true
+}
+
+// ScriptStream sets the sequence of SSE events a streaming request to
+// path emits, in order. After the last event the connection stays open,
+// sending keep-alive comments, until the caller cancels; an event with
+// Close set ends the connection early instead.
+func (s *Server) ScriptStream(path string, events []ScriptedEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scripts[path] = events
+}
+
+func (s *Server) takeInjectedError(path string) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	errs := s.injectedErrors[path]
+	if len(errs) == 0 {
+		return 0, false
+	}
+	s.injectedErrors[path] = errs[1:]
+	return errs[0], true
+}
+
+func (s *Server) takeRedirectOnce(path string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.redirectOnce[path] {
+		delete(s.redirectOnce, path)
+		return true
+	}
+	return false
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+
+	if status, ok := s.takeInjectedError(path); ok {
+		writeError(w, status)
+		return
+	}
+	if s.takeRedirectOnce(path) {
+		http.Redirect(w, r, s.URL+r.URL.RequestURI(), http.StatusTemporaryRedirect)
+		return
+	}
+
+	switch {
+	case path == "/structures.json" && r.Method == "GET":
+		if r.Header.Get("Accept") == "text/event-stream" {
+			s.serveStream(w, r, path)
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		writeJSON(w, s.structures)
+
+	case path == "/devices/thermostats.json" && r.Method == "GET":
+		if r.Header.Get("Accept") == "text/event-stream" {
+			s.serveStream(w, r, path)
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		writeJSON(w, s.thermostats)
+
+	case strings.HasSuffix(path, "/eta.json") && r.Method == "PUT":
+		w.WriteHeader(http.StatusOK)
+
+	case strings.HasPrefix(path, "/devices/thermostats/") && r.Method == "PUT":
+		id := strings.TrimPrefix(path, "/devices/thermostats/")
+		s.putThermostat(w, r, id)
+
+	case strings.HasPrefix(path, "/structures/") && r.Method == "PUT":
+		id := strings.TrimPrefix(path, "/structures/")
+		s.putStructure(w, r, id)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) putThermostat(w http.ResponseWriter, r *http.Request, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	thermostat, ok := s.thermostats[id]
+	if !ok {
+		thermostat = &nest.Thermostat{DeviceID: id}
+		s.thermostats[id] = thermostat
+	}
+	body, _ := ioutil.ReadAll(r.Body)
+	json.Unmarshal(body, thermostat)
+	writeJSON(w, thermostat)
+}
+
+func (s *Server) putStructure(w http.ResponseWriter, r *http.Request, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	structure, ok := s.structures[id]
+	if !ok {
+		structure = &nest.Structure{StructureID: id}
+		s.structures[id] = structure
+	}
+	body, _ := ioutil.ReadAll(r.Body)
+	json.Unmarshal(body, structure)
+	writeJSON(w, structure)
+}
+
+// serveStream replays the events scripted via ScriptStream for path, then
+// sends SSE keep-alive comments until the client disconnects. If the
+// client sends Last-Event-ID, scripted events up to and including that ID
+// are skipped, so tests can exercise resumption.
+func (s *Server) serveStream(w http.ResponseWriter, r *http.Request, path string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+
+	s.mu.Lock()
+	events := s.scripts[path]
+	s.mu.Unlock()
+
+	if lastID := r.Header.Get("Last-Event-ID"); lastID != "" {
+		for i, event := range events {
+			if event.ID == lastID {
+				events = events[i+1:]
+				break
+			}
+		}
+	}
+
+	for _, event := range events {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(event.Delay):
+		}
+		if event.ID != "" {
+			fmt.Fprintf(w, "id: %s\n", event.ID)
+		}
+		if event.Event != "" {
+			fmt.Fprintf(w, "event: %s\n", event.Event)
+		}
+		if event.Retry > 0 {
+			fmt.Fprintf(w, "retry: %d\n", event.Retry.Milliseconds())
+		}
+		fmt.Fprintf(w, "data: %s\n\n", event.Data)
+		flusher.Flush()
+		if event.Close {
+			return
+		}
+	}
+
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	data, _ := json.Marshal(v)
+	w.Write(data)
+}
+
+func writeError(w http.ResponseWriter, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": http.StatusText(status)})
+}
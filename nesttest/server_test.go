@@ -0,0 +1,55 @@
+package nesttest_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/eyesight-tech/nest"
+	"github.com/eyesight-tech/nest/nesttest"
+)
+
+func TestStructuresStreamReconnectsAndResumesFromLastEventID(t *testing.T) {
+	server := nesttest.NewServer()
+	defer server.Close()
+
+	server.ScriptStream("/structures.json", []nesttest.ScriptedEvent{
+		{
+			ID:    "1",
+			Data:  `{"data":{"structure1":{"structure_id":"structure1","name":"Home"}}}`,
+			Retry: 10 * time.Millisecond,
+			Close: true,
+		},
+		{
+			ID:   "2",
+			Data: `{"data":{"structure1":{"structure_id":"structure1","name":"Away Home"}}}`,
+		},
+	})
+
+	client := server.Client()
+	client.ReadTimeout = time.Second
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	received := make(chan map[string]*nest.Structure, 2)
+	handle := client.StructuresStream(ctx, func(structures map[string]*nest.Structure, err error) {
+		if err != nil {
+			t.Errorf("StructuresStream callback error: %v", err)
+			return
+		}
+		received <- structures
+	})
+	defer handle.Stop()
+
+	for i, want := range []string{"Home", "Away Home"} {
+		select {
+		case structures := <-received:
+			if got := structures["structure1"].Name; got != want {
+				t.Errorf("event %d: Name = %q, want %q", i, got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("event %d: timed out waiting for structures update", i)
+		}
+	}
+}
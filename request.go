@@ -0,0 +1,182 @@
+package nest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// HTTPDoer is the subset of *http.Client that Client needs to make a
+// request. Callers can set Client.HTTPClient to a fake or instrumented
+// implementation (e.g. in tests) instead of hitting the network.
+type HTTPDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+const (
+	// defaultRequestTimeout bounds a single request/retry attempt when ctx
+	// carries no deadline of its own.
+	defaultRequestTimeout = 30 * time.Second
+
+	maxRequestRetries   = 3
+	minRequestRetryWait = 500 * time.Millisecond
+	maxRequestRetryWait = 10 * time.Second
+)
+
+// httpClient returns the Client's configured HTTPDoer, defaulting to
+// http.DefaultClient.
+func (c *Client) httpClient() HTTPDoer {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// doRequest issues method against c.RedirectURL+path with the given body,
+// following the one-time 307 region redirect the Nest API issues and
+// retrying idempotent failures (5xx, 429, connection errors) with
+// exponential backoff, honoring any Retry-After the server sends. It
+// applies defaultRequestTimeout to each attempt if ctx has no deadline,
+// and stops retrying as soon as ctx is done. On success it returns the raw
+// response body; on failure a structured *APIError.
+func (c *Client) doRequest(ctx context.Context, method, path string, body []byte) ([]byte, *APIError) {
+	var lastErr *APIError
+	for attempt := 0; attempt <= maxRequestRetries; attempt++ {
+		if attempt > 0 {
+			if apiErr := waitForRetry(ctx, attempt, lastErr); apiErr != nil {
+				return nil, apiErr
+			}
+		}
+
+		respBody, apiErr := c.doRequestOnce(ctx, method, path, body)
+		if apiErr == nil {
+			return respBody, nil
+		}
+		lastErr = apiErr
+		if !isRetryable(apiErr) {
+			return nil, apiErr
+		}
+	}
+	return nil, lastErr
+}
+
+// doRequestOnce performs a single request attempt, transparently following
+// a 307 redirect to the region-specific host Nest assigns a token to.
+func (c *Client) doRequestOnce(ctx context.Context, method, path string, body []byte) ([]byte, *APIError) {
+	attemptCtx := ctx
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(ctx, defaultRequestTimeout)
+		defer cancel()
+	}
+
+	redirected := false
+	for {
+		resp, err := c.doHTTP(attemptCtx, method, c.redirectURL()+path, body)
+		if err != nil {
+			return nil, &APIError{Error: "http_error", Description: err.Error()}
+		}
+
+		if resp.StatusCode == 307 && !redirected {
+			resp.Body.Close()
+			if loc, err := resp.Location(); err == nil {
+				c.setRedirectURLValue(loc.Scheme + "://" + loc.Host)
+			}
+			redirected = true
+			continue
+		}
+
+		respBody, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, &APIError{Error: "body_read_error", Description: err.Error()}
+		}
+		if resp.StatusCode == 200 {
+			return respBody, nil
+		}
+
+		apiError := &APIError{}
+		json.Unmarshal(respBody, apiError)
+		apiError = generateAPIError(apiError.Error)
+		apiError.Status = resp.Status
+		apiError.StatusCode = resp.StatusCode
+		apiError.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+		return nil, apiError
+	}
+}
+
+// doHTTP builds and issues a single HTTP request.
+func (c *Client) doHTTP(ctx context.Context, method, url string, body []byte) (*http.Response, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewBuffer(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.httpClient().Do(req)
+}
+
+// isRetryable reports whether apiErr represents a transient failure worth
+// retrying: a connection error, a 5xx, or a 429.
+func isRetryable(apiErr *APIError) bool {
+	if apiErr.StatusCode == 0 {
+		return apiErr.Error == "http_error"
+	}
+	return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500
+}
+
+// waitForRetry sleeps before the next retry attempt, honoring ctx
+// cancellation and any server-supplied Retry-After from the previous
+// failure, or exponential backoff with jitter otherwise.
+func waitForRetry(ctx context.Context, attempt int, lastErr *APIError) *APIError {
+	wait := backoffRequest(attempt)
+	if lastErr != nil && lastErr.RetryAfter > 0 {
+		wait = lastErr.RetryAfter
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return &APIError{Error: "context_error", Description: ctx.Err().Error()}
+	case <-timer.C:
+		return nil
+	}
+}
+
+// backoffRequest doubles minRequestRetryWait per attempt, capped at
+// maxRequestRetryWait, with up to 50% jitter.
+func backoffRequest(attempt int) time.Duration {
+	wait := minRequestRetryWait
+	for i := 1; i < attempt && wait < maxRequestRetryWait; i++ {
+		wait *= 2
+	}
+	if wait > maxRequestRetryWait {
+		wait = maxRequestRetryWait
+	}
+	jitter := time.Duration(rand.Int63n(int64(wait)/2 + 1))
+	return wait/2 + jitter
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds. It returns
+// 0 if the header is absent or not a delta-seconds value.
+func parseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	seconds, err := strconv.Atoi(header)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,65 @@
+package nest
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	cases := []struct {
+		header string
+		want   time.Duration
+	}{
+		{"", 0},
+		{"5", 5 * time.Second},
+		{"not-a-number", 0},
+	}
+	for _, c := range cases {
+		if got := parseRetryAfter(c.header); got != c.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", c.header, got, c.want)
+		}
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	cases := []struct {
+		apiErr *APIError
+		want   bool
+	}{
+		{&APIError{Error: "http_error"}, true},
+		{&APIError{Error: "body_read_error"}, false},
+		{&APIError{StatusCode: http.StatusTooManyRequests}, true},
+		{&APIError{StatusCode: http.StatusInternalServerError}, true},
+		{&APIError{StatusCode: http.StatusBadRequest}, false},
+	}
+	for _, c := range cases {
+		if got := isRetryable(c.apiErr); got != c.want {
+			t.Errorf("isRetryable(%+v) = %v, want %v", c.apiErr, got, c.want)
+		}
+	}
+}
+
+func TestWaitForRetryHonorsRetryAfterOverBackoff(t *testing.T) {
+	start := time.Now()
+	apiErr := waitForRetry(context.Background(), 1, &APIError{RetryAfter: 20 * time.Millisecond})
+	if apiErr != nil {
+		t.Fatalf("waitForRetry: %v", apiErr)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("waitForRetry returned after %v, want at least the server's Retry-After of 20ms", elapsed)
+	}
+	if elapsed := time.Since(start); elapsed >= minRequestRetryWait/2 {
+		t.Errorf("waitForRetry returned after %v, want the 20ms Retry-After instead of the attempt-1 backoff of at least %v", elapsed, minRequestRetryWait/2)
+	}
+}
+
+func TestWaitForRetryHonorsContextCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	apiErr := waitForRetry(ctx, 1, nil)
+	if apiErr == nil {
+		t.Fatal("waitForRetry: expected an error for an already-cancelled context, got nil")
+	}
+}
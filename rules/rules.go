@@ -0,0 +1,189 @@
+/*
+Package rules implements a small expression-based rule engine for reacting
+to nest.Client stream events, in the same spirit as the rule engine used by
+the Bouncer project. A Rule is a declarative "when X happens, do Y"
+binding instead of hand-rolled goroutine plumbing:
+
+	engine := rules.NewEngine()
+	engine.Register(&rules.Rule{
+		Name:     "too-hot-while-away",
+		When:     `Structure.Away == "away" && Thermostat.AmbientTemperatureF > 78`,
+		Do:       rules.SetHvacMode(nest.Cool),
+		CoolDown: 10 * time.Minute,
+	})
+*/
+package rules
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+
+	"github.com/eyesight-tech/nest"
+)
+
+// Event is the context a rule's When expression and Do action are
+// evaluated against: the structure or thermostat a streamed delta was
+// about, the Event built from the delta before it, and the time the
+// delta was observed.
+type Event struct {
+	Structure  *nest.Structure
+	Thermostat *nest.Thermostat
+	Previous   *Event
+	Now        time.Time
+}
+
+// Action is invoked when a rule matches. The typed helpers below (such as
+// SetHvacMode and SetTargetTempF) build one from the corresponding
+// nest.Thermostat setter; RegisterAction wraps an arbitrary
+// callback for cases those don't cover.
+type Action func(event Event) error
+
+// RegisterAction wraps an arbitrary callback as an Action, for reactions
+// that don't map onto one of the typed helpers.
+func RegisterAction(do func(event Event) error) Action {
+	return do
+}
+
+// SetHvacMode returns an Action that sets the matched event's thermostat
+// to mode (nest.Cool, nest.Heat, nest.HeatCool, nest.Off or nest.Eco).
+func SetHvacMode(mode int) Action {
+	return func(event Event) error {
+		if event.Thermostat == nil {
+			return fmt.Errorf("rules: SetHvacMode requires a thermostat event")
+		}
+		if apiErr := event.Thermostat.SetHvacMode(mode); apiErr != nil {
+			return fmt.Errorf("rules: SetHvacMode: %s", apiErr.Description)
+		}
+		return nil
+	}
+}
+
+// SetTargetTempF returns an Action that sets the matched event's
+// thermostat target temperature, in Fahrenheit.
+func SetTargetTempF(temp int) Action {
+	return func(event Event) error {
+		if event.Thermostat == nil {
+			return fmt.Errorf("rules: SetTargetTempF requires a thermostat event")
+		}
+		if apiErr := event.Thermostat.SetTargetTempF(temp); apiErr != nil {
+			return fmt.Errorf("rules: SetTargetTempF: %s", apiErr.Description)
+		}
+		return nil
+	}
+}
+
+// Rule is a single "when When happens, invoke Do" binding. When is
+// compiled once, at Engine.Register, and re-evaluated against every
+// Event the engine sees. CoolDown, if set, suppresses repeat firings of
+// the same rule until it has elapsed.
+type Rule struct {
+	Name     string
+	When     string
+	Do       Action
+	CoolDown time.Duration
+
+	program   *vm.Program
+	lastFired time.Time
+}
+
+// Counters holds Prometheus-style evaluation counters for a registered
+// rule.
+type Counters struct {
+	Evaluations    uint64
+	Matches        uint64
+	ActionFailures uint64
+}
+
+// Engine evaluates a set of registered Rules against streamed Events.
+// It is safe for concurrent use.
+type Engine struct {
+	mu       sync.Mutex
+	rules    []*Rule
+	counters map[string]*Counters
+}
+
+// NewEngine returns an empty rule Engine.
+func NewEngine() *Engine {
+	return &Engine{counters: make(map[string]*Counters)}
+}
+
+// Register compiles rule.When against an Event environment and adds it to
+// the engine. It returns an error if the expression fails to compile or
+// doesn't evaluate to a bool.
+func (e *Engine) Register(rule *Rule) error {
+	program, err := expr.Compile(rule.When, expr.Env(Event{}), expr.AsBool())
+	if err != nil {
+		return fmt.Errorf("rules: compiling rule %q: %w", rule.Name, err)
+	}
+	rule.program = program
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.rules = append(e.rules, rule)
+	e.counters[rule.Name] = &Counters{}
+	return nil
+}
+
+// Evaluate runs every registered rule against event, invoking Do for each
+// rule whose When expression matches and whose CoolDown has elapsed since
+// it last fired.
+func (e *Engine) Evaluate(event Event) {
+	e.mu.Lock()
+	rules := make([]*Rule, len(e.rules))
+	copy(rules, e.rules)
+	e.mu.Unlock()
+
+	for _, rule := range rules {
+		e.evaluateRule(rule, event)
+	}
+}
+
+// evaluateRule runs a single rule's When expression against event and, on
+// a match outside its cool-down, invokes Do and tallies the result.
+func (e *Engine) evaluateRule(rule *Rule, event Event) {
+	e.mu.Lock()
+	counters := e.counters[rule.Name]
+	counters.Evaluations++
+	e.mu.Unlock()
+
+	output, err := expr.Run(rule.program, event)
+	if err != nil {
+		return
+	}
+	matched, _ := output.(bool)
+	if !matched {
+		return
+	}
+
+	e.mu.Lock()
+	if rule.CoolDown > 0 && !rule.lastFired.IsZero() && event.Now.Sub(rule.lastFired) < rule.CoolDown {
+		e.mu.Unlock()
+		return
+	}
+	rule.lastFired = event.Now
+	counters.Matches++
+	e.mu.Unlock()
+
+	if rule.Do == nil {
+		return
+	}
+	if err := rule.Do(event); err != nil {
+		e.mu.Lock()
+		counters.ActionFailures++
+		e.mu.Unlock()
+	}
+}
+
+// Counters returns a snapshot of the named rule's evaluation counters.
+func (e *Engine) Counters(name string) Counters {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if c, ok := e.counters[name]; ok {
+		return *c
+	}
+	return Counters{}
+}
@@ -0,0 +1,110 @@
+package rules_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/eyesight-tech/nest"
+	"github.com/eyesight-tech/nest/rules"
+)
+
+func TestEngineEvaluateMatchesAndInvokesDo(t *testing.T) {
+	engine := rules.NewEngine()
+	fired := make(chan *nest.Thermostat, 1)
+	err := engine.Register(&rules.Rule{
+		Name: "too-hot-while-away",
+		When: `Structure.Away == "away" && Thermostat.AmbientTemperatureF > 78`,
+		Do: rules.RegisterAction(func(event rules.Event) error {
+			fired <- event.Thermostat
+			return nil
+		}),
+	})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	event := rules.Event{
+		Structure:  &nest.Structure{Away: "away"},
+		Thermostat: &nest.Thermostat{AmbientTemperatureF: 80},
+		Now:        time.Now(),
+	}
+	engine.Evaluate(event)
+
+	select {
+	case thermostat := <-fired:
+		if thermostat.AmbientTemperatureF != 80 {
+			t.Errorf("AmbientTemperatureF = %d, want 80", thermostat.AmbientTemperatureF)
+		}
+	default:
+		t.Fatal("Do was not invoked for a matching event")
+	}
+
+	counters := engine.Counters("too-hot-while-away")
+	if counters.Evaluations != 1 || counters.Matches != 1 {
+		t.Errorf("counters = %+v, want 1 evaluation and 1 match", counters)
+	}
+
+	// A non-matching event should not fire Do or count as a match.
+	engine.Evaluate(rules.Event{
+		Structure:  &nest.Structure{Away: "home"},
+		Thermostat: &nest.Thermostat{AmbientTemperatureF: 80},
+		Now:        time.Now(),
+	})
+	select {
+	case thermostat := <-fired:
+		t.Fatalf("Do invoked unexpectedly for a non-matching event: %+v", thermostat)
+	default:
+	}
+	counters = engine.Counters("too-hot-while-away")
+	if counters.Evaluations != 2 || counters.Matches != 1 {
+		t.Errorf("counters = %+v, want 2 evaluations and 1 match", counters)
+	}
+}
+
+func TestEngineEvaluateCoolDownSuppressesRepeatFiring(t *testing.T) {
+	engine := rules.NewEngine()
+	fireCount := 0
+	if err := engine.Register(&rules.Rule{
+		Name:     "cooldown-rule",
+		When:     `Thermostat.AmbientTemperatureF > 78`,
+		CoolDown: time.Minute,
+		Do: rules.RegisterAction(func(event rules.Event) error {
+			fireCount++
+			return nil
+		}),
+	}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	base := time.Now()
+	event := rules.Event{Thermostat: &nest.Thermostat{AmbientTemperatureF: 80}, Now: base}
+	engine.Evaluate(event)
+	if fireCount != 1 {
+		t.Fatalf("fireCount = %d, want 1 after first match", fireCount)
+	}
+
+	// Still within the cool-down: should not fire again.
+	engine.Evaluate(rules.Event{Thermostat: &nest.Thermostat{AmbientTemperatureF: 80}, Now: base.Add(30 * time.Second)})
+	if fireCount != 1 {
+		t.Fatalf("fireCount = %d, want 1 while still within CoolDown", fireCount)
+	}
+
+	// Past the cool-down: should fire again.
+	engine.Evaluate(rules.Event{Thermostat: &nest.Thermostat{AmbientTemperatureF: 80}, Now: base.Add(2 * time.Minute)})
+	if fireCount != 2 {
+		t.Fatalf("fireCount = %d, want 2 after CoolDown elapsed", fireCount)
+	}
+
+	counters := engine.Counters("cooldown-rule")
+	if counters.Evaluations != 3 || counters.Matches != 2 {
+		t.Errorf("counters = %+v, want 3 evaluations and 2 matches", counters)
+	}
+}
+
+func TestEngineRegisterInvalidExpression(t *testing.T) {
+	engine := rules.NewEngine()
+	err := engine.Register(&rules.Rule{Name: "bad", When: `Thermostat.DoesNotExist`})
+	if err == nil {
+		t.Fatal("Register: expected an error for an invalid When expression, got nil")
+	}
+}
@@ -0,0 +1,53 @@
+package rules
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/eyesight-tech/nest"
+)
+
+// WatchStructures feeds every update from client.StructuresStream into
+// engine as an Event, tracking the previous Event per structure so a
+// rule's When expression can reference Previous. Cancel ctx, or call Stop
+// on the returned handle, to stop watching.
+func WatchStructures(ctx context.Context, client *nest.Client, engine *Engine) *nest.StreamHandle {
+	var mu sync.Mutex
+	previous := make(map[string]*Event)
+	return client.StructuresStream(ctx, func(structures map[string]*nest.Structure, err error) {
+		if err != nil {
+			return
+		}
+		now := time.Now()
+		for id, structure := range structures {
+			mu.Lock()
+			event := Event{Structure: structure, Previous: previous[id], Now: now}
+			previous[id] = &event
+			mu.Unlock()
+			engine.Evaluate(event)
+		}
+	})
+}
+
+// WatchDevices feeds every update from client.DevicesStream into engine as
+// an Event, tracking the previous Event per thermostat so a rule's When
+// expression can reference Previous. Cancel ctx, or call Stop on the
+// returned handle, to stop watching.
+func WatchDevices(ctx context.Context, client *nest.Client, engine *Engine) *nest.StreamHandle {
+	var mu sync.Mutex
+	previous := make(map[string]*Event)
+	return client.DevicesStream(ctx, func(thermostats map[string]*nest.Thermostat, err error) {
+		if err != nil {
+			return
+		}
+		now := time.Now()
+		for id, thermostat := range thermostats {
+			mu.Lock()
+			event := Event{Thermostat: thermostat, Previous: previous[id], Now: now}
+			previous[id] = &event
+			mu.Unlock()
+			engine.Evaluate(event)
+		}
+	})
+}
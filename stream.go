@@ -0,0 +1,193 @@
+package nest
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultStreamRetry = 3 * time.Second
+	maxStreamRetry     = 1 * time.Minute
+	defaultReadTimeout = 60 * time.Second
+)
+
+// StreamHandle controls a stream started by StructuresStream or DevicesStream.
+// Calling Stop cancels the underlying context, which unblocks the
+// reconnect/read loop and lets its goroutine exit.
+type StreamHandle struct {
+	cancel context.CancelFunc
+}
+
+// Stop cancels the stream.
+func (h *StreamHandle) Stop() {
+	h.cancel()
+}
+
+// sseEvent is a single parsed Server-Sent Event frame.
+type sseEvent struct {
+	ID    string
+	Event string
+	Data  string
+}
+
+// runStream connects to url, reconnecting with exponential backoff and
+// jitter until ctx is cancelled. Each reconnect resends the most recently
+// seen event ID as Last-Event-ID so the server can resume the feed.
+// onEvent is invoked for every "data:" frame, onHeartbeat for keep-alive
+// comment lines, and onError whenever a connection attempt fails.
+func (c *Client) runStream(ctx context.Context, url string, onEvent func(sseEvent), onHeartbeat func(), onError func(error)) {
+	retry := defaultStreamRetry
+	attempt := 0
+	for ctx.Err() == nil {
+		nextRetry, err := c.runStreamOnce(ctx, url, c.readTimeout(), onEvent, onHeartbeat)
+		if nextRetry > 0 {
+			retry = nextRetry
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			onError(err)
+		}
+		wait := backoff(retry, attempt)
+		attempt++
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// backoff doubles retry per attempt, capped at maxStreamRetry, and adds up
+// to 50% jitter so reconnecting clients don't all retry in lockstep.
+func backoff(retry time.Duration, attempt int) time.Duration {
+	wait := retry
+	for i := 0; i < attempt && wait < maxStreamRetry; i++ {
+		wait *= 2
+	}
+	if wait > maxStreamRetry {
+		wait = maxStreamRetry
+	}
+	jitter := time.Duration(rand.Int63n(int64(wait)/2 + 1))
+	return wait/2 + jitter
+}
+
+// runStreamOnce makes a single streaming request and reads frames from it
+// until the connection drops, ctx is cancelled, or no data (including
+// keep-alives) is seen within readTimeout. It returns the retry interval
+// the server requested via a "retry:" field, if any.
+func (c *Client) runStreamOnce(ctx context.Context, url string, readTimeout time.Duration, onEvent func(sseEvent), onHeartbeat func()) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if id := c.lastEventID(url); id != "" {
+		req.Header.Set("Last-Event-ID", id)
+	}
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return 0, fmt.Errorf("nest: stream request failed with status %s", resp.Status)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	lines := readLines(resp.Body, done)
+	var retry time.Duration
+	var event sseEvent
+	for {
+		select {
+		case <-ctx.Done():
+			return retry, nil
+		case <-time.After(readTimeout):
+			return retry, fmt.Errorf("nest: stream stalled, no data for %s", readTimeout)
+		case line, ok := <-lines:
+			if !ok {
+				return retry, nil
+			}
+			if line == "" {
+				if event.Data != "" {
+					onEvent(event)
+				}
+				event = sseEvent{}
+				continue
+			}
+			if strings.HasPrefix(line, ":") {
+				onHeartbeat()
+				continue
+			}
+			field, value := splitSSEField(line)
+			switch field {
+			case "id":
+				event.ID = value
+				c.setLastEventID(url, value)
+			case "event":
+				event.Event = value
+			case "data":
+				if event.Data != "" {
+					event.Data += "\n"
+				}
+				event.Data += value
+			case "retry":
+				if ms, err := strconv.Atoi(value); err == nil {
+					retry = time.Duration(ms) * time.Millisecond
+				}
+			}
+		}
+	}
+}
+
+// splitSSEField splits a raw SSE line into its field name and value, per
+// the spec's "field: value" / "field:value" grammar.
+func splitSSEField(line string) (field, value string) {
+	idx := strings.IndexByte(line, ':')
+	if idx == -1 {
+		return line, ""
+	}
+	field = line[:idx]
+	value = strings.TrimPrefix(line[idx+1:], " ")
+	return field, value
+}
+
+// readLines streams newline-delimited, trailing-whitespace-trimmed lines
+// from r on a background goroutine until it errors or EOF, then closes the
+// channel. Running the read on its own goroutine lets the caller select on
+// a read timeout without blocking on the underlying connection. The
+// goroutine also selects on done so that if the caller stops reading
+// lines (e.g. it gave up on a stalled or cancelled connection) before r
+// is exhausted, the goroutine can still exit instead of blocking forever
+// on a send nobody will receive.
+func readLines(r io.Reader, done <-chan struct{}) <-chan string {
+	out := make(chan string)
+	go func() {
+		defer close(out)
+		reader := bufio.NewReader(r)
+		for {
+			line, err := reader.ReadString('\n')
+			if line != "" {
+				select {
+				case out <- strings.TrimRight(line, "\r\n"):
+				case <-done:
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return out
+}
@@ -1,12 +1,8 @@
 package nest
 
 import (
-	"bufio"
-	"bytes"
+	"context"
 	"encoding/json"
-	"fmt"
-	"io/ioutil"
-	"net/http"
 	"time"
 )
 
@@ -17,45 +13,65 @@ https://developer.nest.com/documentation/api#structures
 	structures := client.Structures()
 */
 func (c *Client) Structures() (map[string]*Structure, *APIError) {
-	resp, err := c.getStructures(NoStream)
-	if err != nil {
-		return nil, &APIError{
-			Error:       "devices_error",
-			Description: err.Error(),
-		}
-	}
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
+	return c.StructuresContext(context.Background())
+}
 
-	if err != nil {
-		return nil, &APIError{
-			Error:       "body_read_error",
-			Description: err.Error(),
-		}
-	}
-	if resp.StatusCode != 200 {
-		apiError := &APIError{}
-		json.Unmarshal(body, apiError)
-		return nil, apiError
+// StructuresContext is Structures with a caller-supplied context.Context
+// for cancellation and deadlines.
+func (c *Client) StructuresContext(ctx context.Context) (map[string]*Structure, *APIError) {
+	c.setRedirectURL(ctx)
+	body, apiErr := c.doRequest(ctx, "GET", "/structures.json?auth="+c.Token, nil)
+	if apiErr != nil {
+		return nil, apiErr
 	}
 	structures := make(map[string]*Structure)
-	err = json.Unmarshal(body, &structures)
+	json.Unmarshal(body, &structures)
 	c.associateClientToStructures(structures)
 	return structures, nil
 }
 
 /*
-Structures Stream emits events from the Nest structures REST streaming API
+StructuresStream emits events from the Nest structures REST streaming API.
 
-	client.StructuresStream(func(event map[string]*Structure) {
-		fmt.Println(event)
+It reconnects automatically with exponential backoff and jitter, resuming
+from the last event seen via Last-Event-ID, and treats SSE keep-alive
+comments as a heartbeat: if none arrive (and no event arrives) within
+ReadTimeout the connection is considered stalled and is torn down and
+retried. Cancel ctx, or call Stop on the returned handle, to end the stream.
+
+	handle := client.StructuresStream(ctx, func(structures map[string]*Structure, err error) {
+		fmt.Println(structures, err)
 	})
+	defer handle.Stop()
 */
-func (c *Client) StructuresStream(callback func(structures map[string]*Structure, err error)) {
-	c.setRedirectURL()
-	for {
-		c.streamStructures(callback)
-	}
+func (c *Client) StructuresStream(ctx context.Context, callback func(structures map[string]*Structure, err error)) *StreamHandle {
+	c.setRedirectURL(ctx)
+	streamCtx, cancel := context.WithCancel(ctx)
+	go c.runStream(streamCtx, c.structuresStreamURL(),
+		func(event sseEvent) {
+			structuresEvent := &StructuresEvent{}
+			if err := json.Unmarshal([]byte(event.Data), structuresEvent); err != nil {
+				callback(nil, err)
+				return
+			}
+			if structuresEvent.Data != nil {
+				c.associateClientToStructures(structuresEvent.Data)
+				callback(structuresEvent.Data, nil)
+			}
+		},
+		func() {},
+		func(err error) {
+			callback(nil, err)
+		},
+	)
+	return &StreamHandle{cancel: cancel}
+}
+
+// structuresStreamURL builds the structures streaming endpoint URL from the
+// RedirectURL that setRedirectURL discovered; it does not run discovery
+// itself.
+func (c *Client) structuresStreamURL() string {
+	return c.redirectURL() + "/structures.json?auth=" + c.Token
 }
 
 /*
@@ -65,6 +81,12 @@ https://developer.nest.com/documentation/api#away
 	s.SetAway(nest.Away)
 */
 func (s *Structure) SetAway(mode int) *APIError {
+	return s.SetAwayContext(context.Background(), mode)
+}
+
+// SetAwayContext is SetAway with a caller-supplied context.Context for
+// cancellation and deadlines.
+func (s *Structure) SetAwayContext(ctx context.Context, mode int) *APIError {
 	requestMode := make(map[string]string)
 	switch mode {
 	case Home:
@@ -77,45 +99,33 @@ func (s *Structure) SetAway(mode int) *APIError {
 		return generateAPIError("Invalid Away requested - must be home, away or auto-away")
 	}
 	body, _ := json.Marshal(requestMode)
-	return s.setStructure(body)
+	return s.setStructure(ctx, body)
 }
 
 /*
 SetETA sets the ETA for the Nest API
 https://developer.nest.com/documentation/eta-reference
-
 */
 func (s *Structure) SetETA(tripID string, begin time.Time, end time.Time) *APIError {
+	return s.SetETAContext(context.Background(), tripID, begin, end)
+}
+
+// SetETAContext is SetETA with a caller-supplied context.Context for
+// cancellation and deadlines.
+func (s *Structure) SetETAContext(ctx context.Context, tripID string, begin time.Time, end time.Time) *APIError {
 	apiErr := checkTimes(begin, end)
 	if apiErr != nil {
 		return apiErr
 	}
 	eta := &ETA{
-		TripID: tripID,
+		TripID:                      tripID,
 		EstimatedArrivalWindowBegin: begin,
 		EstimatedArrivalWindowEnd:   end,
 	}
 	data, _ := json.Marshal(eta)
-	req, _ := http.NewRequest("PUT", s.Client.RedirectURL+"/structures/"+s.StructureID+"/eta.json?auth="+s.Client.Token, bytes.NewBuffer(data))
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		apiError := &APIError{
-			Error:       "http_error",
-			Description: err.Error(),
-		}
-		return apiError
-	}
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
-	if resp.StatusCode != 200 {
-		apiError := &APIError{}
-		json.Unmarshal(body, apiError)
-		apiError = generateAPIError(apiError.Error)
-		apiError.Status = resp.Status
-		apiError.StatusCode = resp.StatusCode
-		return apiError
-	}
-	return nil
+	path := "/structures/" + s.StructureID + "/eta.json?auth=" + s.Client.Token
+	_, apiErr = s.Client.doRequest(ctx, "PUT", path, data)
+	return apiErr
 }
 
 // checkTimes ensure the times provided are set properly for the Nest API
@@ -137,102 +147,16 @@ func checkTimes(begin time.Time, end time.Time) *APIError {
 	return nil
 }
 
-// streamStructures connects to the stream, following the redirect and then watches the stream
-func (c *Client) streamStructures(callback func(structures map[string]*Structure, err error)) {
-	resp, err := c.getStructures(Stream)
-	if err != nil {
-		callback(nil, err)
-		return
-	}
-	defer resp.Body.Close()
-	c.watchStructuresStream(resp, callback)
-}
-
-// watchStructuresStream grabs the data off the stream, parses them and invokes the callback
-func (c *Client) watchStructuresStream(resp *http.Response, callback func(structures map[string]*Structure, err error)) {
-	reader := bufio.NewReader(resp.Body)
-	for {
-		line, err := reader.ReadString('\n')
-		if err != nil {
-			return
-		}
-		value := parseStreamData(line)
-		if value != "" {
-			structuresEvent := &StructuresEvent{}
-			json.Unmarshal([]byte(value), structuresEvent)
-			if structuresEvent.Data != nil {
-				c.associateClientToStructures(structuresEvent.Data)
-				callback(structuresEvent.Data, nil)
-			}
-		}
-	}
-}
-
-// getStructures does an HTTP get
-func (c *Client) getStructures(action int) (*http.Response, error) {
-	if c.RedirectURL == "" {
-		req, _ := http.NewRequest("GET", c.APIURL+"/structures.json?auth="+c.Token, nil)
-		resp, err := http.DefaultClient.Do(req)
-		if resp.Request.URL != nil {
-			c.RedirectURL = resp.Request.URL.Scheme + "://" + resp.Request.URL.Host
-		}
-		return resp, err
-	}
-
-	req, _ := http.NewRequest("GET", c.RedirectURL+"/structures.json?auth="+c.Token, nil)
-	if action == Stream {
-		req.Header.Set("Accept", "text/event-stream")
-	}
-	resp, err := http.DefaultClient.Do(req)
-	return resp, err
-}
-
-// setStructure sends the request to the Nest REST API
-func (s *Structure) setStructure(body []byte) *APIError {
-
-	url := s.Client.RedirectURL + "/structures/" + s.StructureID + "?auth=" + s.Client.Token
-	client := &http.Client{}
-	req, _ := http.NewRequest("PUT", url, bytes.NewBuffer(body))
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Println("Error before redirect! ", err)
-		apiError := &APIError{
-			Error:       "http_error",
-			Description: err.Error(),
-		}
-		return apiError
-	}
-	if resp.StatusCode == 307 {
-		fmt.Println("307 after request! ")
-		s.Client.RedirectURL = resp.Request.URL.Scheme + "://" + resp.Request.URL.Host
-		url := s.Client.RedirectURL + "/structures/" + s.StructureID + "?auth=" + s.Client.Token
-		req, _ := http.NewRequest("PUT", url, bytes.NewBuffer(body))
-		req.Header.Set("Content-Type", "application/json")
-		response, err := client.Do(req)
-		if err != nil {
-			fmt.Println("Error after redirect! ", err)
-			apiError := &APIError{
-				Error:       "http_error",
-				Description: err.Error(),
-			}
-			return apiError
-		}
-		resp = response
-	}
-	body, _ = ioutil.ReadAll(resp.Body)
-	defer resp.Body.Close()
-	if resp.StatusCode == 200 {
-		structure := &Structure{}
-		json.Unmarshal(body, structure)
-		return nil
+// setStructure sends the request to the Nest REST API and applies the
+// resulting state onto s, so a subsequent call sees the effect of this one.
+func (s *Structure) setStructure(ctx context.Context, body []byte) *APIError {
+	path := "/structures/" + s.StructureID + "?auth=" + s.Client.Token
+	respBody, apiErr := s.Client.doRequest(ctx, "PUT", path, body)
+	if apiErr != nil {
+		return apiErr
 	}
-	apiError := &APIError{}
-	json.Unmarshal(body, apiError)
-	apiError = generateAPIError(apiError.Error)
-	apiError.Status = resp.Status
-	apiError.StatusCode = resp.StatusCode
-	return apiError
+	json.Unmarshal(respBody, s)
+	return nil
 }
 
 // associateClientToStructures ensures each structure knows its client details
@@ -1,13 +1,61 @@
 package nest
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
-	"fmt"
-	"io/ioutil"
-	"net/http"
 )
 
+/*
+DevicesStream emits events from the Nest thermostats REST streaming API.
+
+Like StructuresStream, it reconnects automatically with exponential backoff
+and jitter, resumes from the last event seen via Last-Event-ID, and treats
+a stall (no event or keep-alive within Client.ReadTimeout) as a dropped
+connection to retry. Cancel ctx, or call Stop on the returned handle, to
+end the stream.
+
+	handle := client.DevicesStream(ctx, func(thermostats map[string]*Thermostat, err error) {
+		fmt.Println(thermostats, err)
+	})
+	defer handle.Stop()
+*/
+func (c *Client) DevicesStream(ctx context.Context, callback func(thermostats map[string]*Thermostat, err error)) *StreamHandle {
+	c.setRedirectURL(ctx)
+	streamCtx, cancel := context.WithCancel(ctx)
+	go c.runStream(streamCtx, c.devicesStreamURL(),
+		func(event sseEvent) {
+			devicesEvent := &DevicesEvent{}
+			if err := json.Unmarshal([]byte(event.Data), devicesEvent); err != nil {
+				callback(nil, err)
+				return
+			}
+			if devicesEvent.Data != nil {
+				c.associateClientToThermostats(devicesEvent.Data)
+				callback(devicesEvent.Data, nil)
+			}
+		},
+		func() {},
+		func(err error) {
+			callback(nil, err)
+		},
+	)
+	return &StreamHandle{cancel: cancel}
+}
+
+// devicesStreamURL builds the thermostats streaming endpoint URL from the
+// RedirectURL that setRedirectURL discovered; it does not run discovery
+// itself.
+func (c *Client) devicesStreamURL() string {
+	return c.redirectURL() + "/devices/thermostats.json?auth=" + c.Token
+}
+
+// associateClientToThermostats ensures each thermostat knows its client details
+func (c *Client) associateClientToThermostats(thermostats map[string]*Thermostat) {
+	for _, value := range thermostats {
+		value.Client = c
+	}
+}
+
 /*
 SetFanTimerActive sets the fan timer on or off
 https://developer.nest.com/documentation/api#fan_timer_active
@@ -15,10 +63,16 @@ https://developer.nest.com/documentation/api#fan_timer_active
 	t.SetFanTimerActive(true)
 */
 func (t *Thermostat) SetFanTimerActive(setting bool) *APIError {
+	return t.SetFanTimerActiveContext(context.Background(), setting)
+}
+
+// SetFanTimerActiveContext is SetFanTimerActive with a caller-supplied
+// context.Context for cancellation and deadlines.
+func (t *Thermostat) SetFanTimerActiveContext(ctx context.Context, setting bool) *APIError {
 	request := make(map[string]bool)
 	request["fan_timer_active"] = setting
 	body, _ := json.Marshal(request)
-	return t.setThermostat(body)
+	return t.setThermostat(ctx, body)
 }
 
 /*
@@ -28,6 +82,12 @@ https://developer.nest.com/documentation/api#hvac_mode
 	t.SetHvacMode(Cool)
 */
 func (t *Thermostat) SetHvacMode(mode int) *APIError {
+	return t.SetHvacModeContext(context.Background(), mode)
+}
+
+// SetHvacModeContext is SetHvacMode with a caller-supplied context.Context
+// for cancellation and deadlines.
+func (t *Thermostat) SetHvacModeContext(ctx context.Context, mode int) *APIError {
 	requestMode := make(map[string]string)
 	switch mode {
 	case Cool:
@@ -38,11 +98,27 @@ func (t *Thermostat) SetHvacMode(mode int) *APIError {
 		requestMode["hvac_mode"] = "heat-cool"
 	case Off:
 		requestMode["hvac_mode"] = "off"
+	case Eco:
+		requestMode["hvac_mode"] = "eco"
 	default:
-		return generateAPIError("Invalid HvacMode requested - must be cool, heat, heat-cool or off")
+		return generateAPIError("Invalid HvacMode requested - must be cool, heat, heat-cool, off or eco")
 	}
 	body, _ := json.Marshal(requestMode)
-	return t.setThermostat(body)
+	return t.setThermostat(ctx, body)
+}
+
+// checkTargetTempWritable rejects target temperature changes the Nest API
+// treats as read-only: while the thermostat is in Eco mode the target
+// temperature is computed automatically, and while it's locked only the
+// locked temperature range can be changed.
+func (t *Thermostat) checkTargetTempWritable() *APIError {
+	if t.HvacMode == "eco" {
+		return generateAPIError("Target temperature is read-only while HvacMode is eco")
+	}
+	if t.Locked {
+		return generateAPIError("Target temperature is read-only while the thermostat is locked")
+	}
+	return nil
 }
 
 /*
@@ -52,13 +128,22 @@ https://developer.nest.com/documentation/api#target_temperature_c
 	t.SetTargetTempC(28.5)
 */
 func (t *Thermostat) SetTargetTempC(temp float32) *APIError {
+	return t.SetTargetTempCContext(context.Background(), temp)
+}
+
+// SetTargetTempCContext is SetTargetTempC with a caller-supplied
+// context.Context for cancellation and deadlines.
+func (t *Thermostat) SetTargetTempCContext(ctx context.Context, temp float32) *APIError {
+	if apiErr := t.checkTargetTempWritable(); apiErr != nil {
+		return apiErr
+	}
 	if temp < 9 || temp > 32 {
 		return generateAPIError("Temperature must be between 9 and 32 Celcius")
 	}
 	tempRequest := make(map[string]float32)
 	tempRequest["target_temperature_c"] = temp
 	body, _ := json.Marshal(tempRequest)
-	return t.setThermostat(body)
+	return t.setThermostat(ctx, body)
 }
 
 /*
@@ -68,13 +153,22 @@ https://developer.nest.com/documentation/api#target_temperature_f
 	t.SetTargetTempF(78)
 */
 func (t *Thermostat) SetTargetTempF(temp int) *APIError {
+	return t.SetTargetTempFContext(context.Background(), temp)
+}
+
+// SetTargetTempFContext is SetTargetTempF with a caller-supplied
+// context.Context for cancellation and deadlines.
+func (t *Thermostat) SetTargetTempFContext(ctx context.Context, temp int) *APIError {
+	if apiErr := t.checkTargetTempWritable(); apiErr != nil {
+		return apiErr
+	}
 	if temp < 50 || temp > 90 {
 		return generateAPIError("Temperature must be between 50 and 90 Farenheit")
 	}
 	request := make(map[string]int)
 	request["target_temperature_f"] = temp
 	body, _ := json.Marshal(request)
-	return t.setThermostat(body)
+	return t.setThermostat(ctx, body)
 }
 
 /*
@@ -85,6 +179,15 @@ https://developer.nest.com/documentation/api#target_temperature_low_c
 	t.SetTargetTempHighLowF(75, 65)
 */
 func (t *Thermostat) SetTargetTempHighLowC(high float32, low float32) *APIError {
+	return t.SetTargetTempHighLowCContext(context.Background(), high, low)
+}
+
+// SetTargetTempHighLowCContext is SetTargetTempHighLowC with a
+// caller-supplied context.Context for cancellation and deadlines.
+func (t *Thermostat) SetTargetTempHighLowCContext(ctx context.Context, high float32, low float32) *APIError {
+	if apiErr := t.checkTargetTempWritable(); apiErr != nil {
+		return apiErr
+	}
 	if high < low {
 		return generateAPIError("The high temperature must be greater than the low temperature")
 	}
@@ -92,7 +195,7 @@ func (t *Thermostat) SetTargetTempHighLowC(high float32, low float32) *APIError
 	request["target_temperature_high_c"] = high
 	request["target_temperature_low_c"] = low
 	body, _ := json.Marshal(request)
-	return t.setThermostat(body)
+	return t.setThermostat(ctx, body)
 }
 
 /*
@@ -103,6 +206,15 @@ https://developer.nest.com/documentation/api#target_temperature_low_f
 	t.SetTargetTempHighLowF(75, 65)
 */
 func (t *Thermostat) SetTargetTempHighLowF(high int, low int) *APIError {
+	return t.SetTargetTempHighLowFContext(context.Background(), high, low)
+}
+
+// SetTargetTempHighLowFContext is SetTargetTempHighLowF with a
+// caller-supplied context.Context for cancellation and deadlines.
+func (t *Thermostat) SetTargetTempHighLowFContext(ctx context.Context, high int, low int) *APIError {
+	if apiErr := t.checkTargetTempWritable(); apiErr != nil {
+		return apiErr
+	}
 	if high < low {
 		return generateAPIError("The high temperature must be greater than the low temperature")
 	}
@@ -110,54 +222,173 @@ func (t *Thermostat) SetTargetTempHighLowF(high int, low int) *APIError {
 	request["target_temperature_high_f"] = high
 	request["target_temperature_low_f"] = low
 	body, _ := json.Marshal(request)
-	return t.setThermostat(body)
-}
-
-// setThermostat sends the request to the Nest REST API
-func (t *Thermostat) setThermostat(body []byte) *APIError {
-	url := t.Client.RedirectURL + "/devices/thermostats/" + t.DeviceID + "?auth=" + t.Client.Token
-	client := &http.Client{}
-	req, _ := http.NewRequest("PUT", url, bytes.NewBuffer(body))
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Println("Error before redirect! ", err)
-		apiError := &APIError{
-			Error:       "http_error",
-			Description: err.Error(),
-		}
-		return apiError
-	}
-	if resp.StatusCode == 307 {
-		fmt.Println("307 after request! ")
-		t.Client.RedirectURL = resp.Request.URL.Scheme + "://" + resp.Request.URL.Host
-		url := t.Client.RedirectURL + "/devices/thermostats/" + t.DeviceID + "?auth=" + t.Client.Token
-		req, _ := http.NewRequest("PUT", url, bytes.NewBuffer(body))
-		req.Header.Set("Content-Type", "application/json")
-		response, err := client.Do(req)
-		if err != nil {
-			fmt.Println("Error after redirect! ", err)
-			apiError := &APIError{
-				Error:       "http_error",
-				Description: err.Error(),
-			}
-			return apiError
-		}
-		resp = response
-	}
-	body, _ = ioutil.ReadAll(resp.Body)
-	defer resp.Body.Close()
-	if resp.StatusCode == 200 {
-		thermostat := &Thermostat{}
-		json.Unmarshal(body, thermostat)
-		return nil
-	}
-	apiError := &APIError{}
-	json.Unmarshal(body, apiError)
-	apiError = generateAPIError(apiError.Error)
-	apiError.Status = resp.Status
-	apiError.StatusCode = resp.StatusCode
-	return apiError
+	return t.setThermostat(ctx, body)
+}
+
+/*
+SetLocked turns the thermostat's temperature lock on or off. While locked,
+the SetTargetTemp* setters return an error; only the locked temperature
+range can be changed, via SetLockedTempMinC/F and SetLockedTempMaxC/F.
+https://developer.nest.com/documentation/api#is_locked
+
+	t.SetLocked(true)
+*/
+func (t *Thermostat) SetLocked(locked bool) *APIError {
+	return t.SetLockedContext(context.Background(), locked)
+}
+
+// SetLockedContext is SetLocked with a caller-supplied context.Context for
+// cancellation and deadlines.
+func (t *Thermostat) SetLockedContext(ctx context.Context, locked bool) *APIError {
+	request := make(map[string]bool)
+	request["is_locked"] = locked
+	body, _ := json.Marshal(request)
+	return t.setThermostat(ctx, body)
+}
+
+/*
+SetLockedTempMinC sets the lower bound, in celcius, the target
+temperature is confined to while the thermostat is locked.
+https://developer.nest.com/documentation/api#locked_temp_min_c
+
+	t.SetLockedTempMinC(18)
+*/
+func (t *Thermostat) SetLockedTempMinC(temp float32) *APIError {
+	return t.SetLockedTempMinCContext(context.Background(), temp)
+}
+
+// SetLockedTempMinCContext is SetLockedTempMinC with a caller-supplied
+// context.Context for cancellation and deadlines.
+func (t *Thermostat) SetLockedTempMinCContext(ctx context.Context, temp float32) *APIError {
+	if temp < 9 || temp > 32 {
+		return generateAPIError("Temperature must be between 9 and 32 Celcius")
+	}
+	request := make(map[string]float32)
+	request["locked_temp_min_c"] = temp
+	body, _ := json.Marshal(request)
+	return t.setThermostat(ctx, body)
+}
+
+/*
+SetLockedTempMaxC sets the upper bound, in celcius, the target
+temperature is confined to while the thermostat is locked.
+https://developer.nest.com/documentation/api#locked_temp_max_c
+
+	t.SetLockedTempMaxC(24)
+*/
+func (t *Thermostat) SetLockedTempMaxC(temp float32) *APIError {
+	return t.SetLockedTempMaxCContext(context.Background(), temp)
+}
+
+// SetLockedTempMaxCContext is SetLockedTempMaxC with a caller-supplied
+// context.Context for cancellation and deadlines.
+func (t *Thermostat) SetLockedTempMaxCContext(ctx context.Context, temp float32) *APIError {
+	if temp < 9 || temp > 32 {
+		return generateAPIError("Temperature must be between 9 and 32 Celcius")
+	}
+	request := make(map[string]float32)
+	request["locked_temp_max_c"] = temp
+	body, _ := json.Marshal(request)
+	return t.setThermostat(ctx, body)
+}
+
+/*
+SetLockedTempMinF sets the lower bound, in farenheit, the target
+temperature is confined to while the thermostat is locked.
+https://developer.nest.com/documentation/api#locked_temp_min_f
+
+	t.SetLockedTempMinF(65)
+*/
+func (t *Thermostat) SetLockedTempMinF(temp int) *APIError {
+	return t.SetLockedTempMinFContext(context.Background(), temp)
+}
+
+// SetLockedTempMinFContext is SetLockedTempMinF with a caller-supplied
+// context.Context for cancellation and deadlines.
+func (t *Thermostat) SetLockedTempMinFContext(ctx context.Context, temp int) *APIError {
+	if temp < 50 || temp > 90 {
+		return generateAPIError("Temperature must be between 50 and 90 Farenheit")
+	}
+	request := make(map[string]int)
+	request["locked_temp_min_f"] = temp
+	body, _ := json.Marshal(request)
+	return t.setThermostat(ctx, body)
+}
+
+/*
+SetLockedTempMaxF sets the upper bound, in farenheit, the target
+temperature is confined to while the thermostat is locked.
+https://developer.nest.com/documentation/api#locked_temp_max_f
+
+	t.SetLockedTempMaxF(75)
+*/
+func (t *Thermostat) SetLockedTempMaxF(temp int) *APIError {
+	return t.SetLockedTempMaxFContext(context.Background(), temp)
+}
+
+// SetLockedTempMaxFContext is SetLockedTempMaxF with a caller-supplied
+// context.Context for cancellation and deadlines.
+func (t *Thermostat) SetLockedTempMaxFContext(ctx context.Context, temp int) *APIError {
+	if temp < 50 || temp > 90 {
+		return generateAPIError("Temperature must be between 50 and 90 Farenheit")
+	}
+	request := make(map[string]int)
+	request["locked_temp_max_f"] = temp
+	body, _ := json.Marshal(request)
+	return t.setThermostat(ctx, body)
+}
+
+/*
+SetLabel sets the thermostat's display label.
+https://developer.nest.com/documentation/api#label
+
+	t.SetLabel("Upstairs")
+*/
+func (t *Thermostat) SetLabel(label string) *APIError {
+	return t.SetLabelContext(context.Background(), label)
+}
+
+// SetLabelContext is SetLabel with a caller-supplied context.Context for
+// cancellation and deadlines.
+func (t *Thermostat) SetLabelContext(ctx context.Context, label string) *APIError {
+	request := make(map[string]string)
+	request["label"] = label
+	body, _ := json.Marshal(request)
+	return t.setThermostat(ctx, body)
+}
+
+/*
+SetSunlightCorrectionEnabled turns Sunlight Correction, which compensates
+the reported ambient temperature for direct sunlight on the thermostat,
+on or off.
+https://developer.nest.com/documentation/api#sunlight_correction_enabled
+
+	t.SetSunlightCorrectionEnabled(true)
+*/
+func (t *Thermostat) SetSunlightCorrectionEnabled(setting bool) *APIError {
+	return t.SetSunlightCorrectionEnabledContext(context.Background(), setting)
+}
+
+// SetSunlightCorrectionEnabledContext is SetSunlightCorrectionEnabled with
+// a caller-supplied context.Context for cancellation and deadlines.
+func (t *Thermostat) SetSunlightCorrectionEnabledContext(ctx context.Context, setting bool) *APIError {
+	request := make(map[string]bool)
+	request["sunlight_correction_enabled"] = setting
+	body, _ := json.Marshal(request)
+	return t.setThermostat(ctx, body)
+}
+
+// setThermostat sends the request to the Nest REST API and applies the
+// resulting state onto t, so a subsequent call sees the effect of this one
+// (e.g. checkTargetTempWritable observing a HvacMode just set to Eco).
+func (t *Thermostat) setThermostat(ctx context.Context, body []byte) *APIError {
+	path := "/devices/thermostats/" + t.DeviceID + "?auth=" + t.Client.Token
+	respBody, apiErr := t.Client.doRequest(ctx, "PUT", path, body)
+	if apiErr != nil {
+		return apiErr
+	}
+	json.Unmarshal(respBody, t)
+	return nil
 }
 
 // generateAPIError generates an error to return when an API call is invalid